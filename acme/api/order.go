@@ -18,6 +18,8 @@ func (o *OrderService) New(domains []string) (acme.ExtendedOrder, error) {
 
 	orderReq := acme.Order{Identifiers: identifiers}
 
+	acme.Throttle()
+
 	var order acme.Order
 	resp, err := o.core.post(o.core.GetDirectory().NewOrderURL, orderReq, &order)
 	if err != nil {
@@ -36,6 +38,8 @@ func (o *OrderService) Get(orderURL string) (acme.ExtendedOrder, error) {
 		return acme.ExtendedOrder{}, errors.New("order[get]: empty URL")
 	}
 
+	acme.Throttle()
+
 	var order acme.Order
 	_, err := o.core.postAsGet(orderURL, &order)
 	if err != nil {
@@ -51,6 +55,8 @@ func (o *OrderService) UpdateForCSR(orderURL string, csr []byte) (acme.ExtendedO
 		Csr: base64.RawURLEncoding.EncodeToString(csr),
 	}
 
+	acme.Throttle()
+
 	var order acme.Order
 	_, err := o.core.post(orderURL, csrMsg, &order)
 	if err != nil {
@@ -0,0 +1,368 @@
+package acme
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const (
+	orderPollInterval = 1 * time.Second
+	orderPollTimeout  = 90 * time.Second
+)
+
+// tlsFeatureExtensionOID is the OID of the TLS Feature X.509 extension
+// (RFC 7633), used below to request OCSP Must-Staple.
+var tlsFeatureExtensionOID = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 24}
+
+// ocspMustStapleFeature is the DER encoding of a TLS Feature extension
+// asserting status_request (5), i.e. OCSP Must-Staple.
+var ocspMustStapleFeature = []byte{0x30, 0x03, 0x02, 0x01, 0x05}
+
+// CertificateResource represents the output of a successful certificate
+// order: the issued certificate chain, alongside enough metadata to renew
+// or revoke it later.
+type CertificateResource struct {
+	Domain            string   `json:"domain"`
+	Domains           []string `json:"domains"`
+	CertURL           string   `json:"certUrl"`
+	PrivateKey        []byte   `json:"-"`
+	Certificate       []byte   `json:"-"`
+	IssuerCertificate []byte   `json:"-"`
+	CSR               []byte   `json:"-"`
+}
+
+// orderService is the subset of api.OrderService's method set that Obtain
+// and Renew need. It's declared here, rather than importing acme/api
+// directly, because that package already imports this one for the Order
+// types - importing it back would create a cycle.
+type orderService interface {
+	New(domains []string) (ExtendedOrder, error)
+	Get(orderURL string) (ExtendedOrder, error)
+	UpdateForCSR(orderURL string, csr []byte) (ExtendedOrder, error)
+}
+
+// SetOrderService wires the ACMEv2 order service that Obtain and Renew
+// poll against. It must be called, typically with an *api.OrderService,
+// before either of them is used.
+func (c *Client) SetOrderService(orders orderService) {
+	c.orders = orders
+}
+
+// SetRevokeCertURL wires the CA's revoke-cert endpoint, as advertised in
+// its directory, for use by RevokeCertificate.
+func (c *Client) SetRevokeCertURL(revokeCertURL string) {
+	c.revokeCertURL = revokeCertURL
+}
+
+// Obtain requests a certificate for the given domains, walking the full
+// ACMEv2 order flow: create the order, solve every pending authorization,
+// wait for the order to become ready, submit the CSR, wait for the order
+// to become valid, and download the issued chain. If privKey is nil, a
+// new certificate private key is generated using the Client's KeyType.
+func (c *Client) Obtain(domains []string, bundle bool, privKey crypto.PrivateKey, mustStaple bool) (*CertificateResource, error) {
+	if c.orders == nil {
+		return nil, errors.New("Obtain: no order service configured, call SetOrderService first")
+	}
+
+	order, err := c.orders.New(domains)
+	if err != nil {
+		return nil, fmt.Errorf("Could not create order for %v: %v", domains, err)
+	}
+	orderURL := order.Location
+
+	authzs, err := c.authorizationsFromOrder(order)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.solveChallenges(authzs); err != nil {
+		return nil, err
+	}
+
+	order, err = c.waitOrder(orderURL, "ready")
+	if err != nil {
+		return nil, err
+	}
+
+	if privKey == nil {
+		privKey, err = GeneratePrivateKey(c.keyType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	csr, err := generateCSR(privKey, domains, mustStaple)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err = c.orders.UpdateForCSR(orderURL, csr)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err = c.waitOrder(orderURL, "valid")
+	if err != nil {
+		return nil, err
+	}
+
+	certPEM, issuerPEM, err := c.downloadCertificate(order.Certificate, bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPEM, err := pemEncodePrivateKey(privKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CertificateResource{
+		Domain:            domains[0],
+		Domains:           domains,
+		CertURL:           order.Certificate,
+		PrivateKey:        keyPEM,
+		Certificate:       certPEM,
+		IssuerCertificate: issuerPEM,
+		CSR:               csr,
+	}, nil
+}
+
+// Renew re-obtains a certificate for an existing CertificateResource,
+// reusing its private key and the full set of domains (including SANs)
+// it was originally issued for.
+func (c *Client) Renew(cert CertificateResource, bundle, mustStaple bool) (*CertificateResource, error) {
+	privKey, err := parsePEMPrivateKey(cert.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse private key for %s: %v", cert.Domain, err)
+	}
+
+	domains := cert.Domains
+	if len(domains) == 0 {
+		domains = []string{cert.Domain}
+	}
+
+	return c.Obtain(domains, bundle, privKey, mustStaple)
+}
+
+// RevokeCertificate revokes a previously issued PEM-encoded certificate
+// against the CA's revoke-cert endpoint, configured via SetRevokeCertURL.
+func (c *Client) RevokeCertificate(cert []byte, reason uint) error {
+	if c.revokeCertURL == "" {
+		return errors.New("RevokeCertificate: no revoke-cert URL configured, call SetRevokeCertURL first")
+	}
+
+	block, _ := pem.Decode(cert)
+	if block == nil {
+		return errors.New("RevokeCertificate: certificate is not valid PEM")
+	}
+
+	jsonBytes, err := json.Marshal(struct {
+		Certificate string `json:"certificate"`
+		Reason      uint   `json:"reason"`
+	}{
+		Certificate: base64.RawURLEncoding.EncodeToString(block.Bytes),
+		Reason:      reason,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.jws.post(c.revokeCertURL, jsonBytes)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Revoking certificate failed: got status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// authorizationsFromOrder fetches every authorization referenced by order
+// and reduces each one down to a single challenge this Client knows how
+// to solve, as one combination, so the existing chooseSolvers/
+// solveChallenges machinery can drive it unchanged.
+func (c *Client) authorizationsFromOrder(order ExtendedOrder) ([]*authorizationResource, error) {
+	var resources []*authorizationResource
+
+	for _, authzURL := range order.Authorizations {
+		resp, err := c.jws.post(authzURL, []byte(""))
+		if err != nil {
+			return nil, fmt.Errorf("Could not fetch authorization %s: %v", authzURL, err)
+		}
+
+		var authz authorization
+		err = json.NewDecoder(limitBody(resp.Body)).Decode(&authz)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		i, ok := indexOfSolvableChallenge(authz.Challenges, c.Solvers)
+		if !ok {
+			return nil, fmt.Errorf("Could not determine how to solve challenge for %s", authz.Identifier.Value)
+		}
+
+		resources = append(resources, &authorizationResource{
+			Body: authorization{
+				Identifier:   authz.Identifier,
+				Challenges:   []challenge{authz.Challenges[i]},
+				Combinations: [][]int{{0}},
+			},
+			Domain: authz.Identifier.Value,
+		})
+	}
+
+	return resources, nil
+}
+
+// indexOfSolvableChallenge returns the index of the first challenge that
+// one of solvers can handle.
+func indexOfSolvableChallenge(challenges []challenge, solvers map[string]solver) (int, bool) {
+	for i, chlng := range challenges {
+		if _, ok := solvers[chlng.Type]; ok {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// waitOrder polls orderURL with exponential backoff until it reaches
+// status, becomes invalid, or orderPollTimeout elapses.
+func (c *Client) waitOrder(orderURL, status string) (ExtendedOrder, error) {
+	deadline := time.Now().Add(orderPollTimeout)
+
+	for wait := orderPollInterval; ; wait *= 2 {
+		order, err := c.orders.Get(orderURL)
+		if err != nil {
+			return ExtendedOrder{}, err
+		}
+
+		switch order.Status {
+		case status:
+			return order, nil
+		case StatusInvalid:
+			return ExtendedOrder{}, fmt.Errorf("Order %s became invalid: %v", orderURL, order.Error)
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return ExtendedOrder{}, fmt.Errorf("Timed out waiting for order %s to become %s", orderURL, status)
+		}
+
+		if wait > remaining {
+			wait = remaining
+		}
+		time.Sleep(wait)
+	}
+}
+
+// downloadCertificate fetches and PEM-decodes the certificate chain
+// returned for a valid order, splitting it into the leaf certificate (and
+// the rest of the chain, if bundle is true) and the issuer certificate.
+func (c *Client) downloadCertificate(certURL string, bundle bool) (cert, issuer []byte, err error) {
+	resp, err := c.jws.post(certURL, []byte(""))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(limitBody(resp.Body))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var blocks []*pem.Block
+	for rest := body; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		blocks = append(blocks, block)
+	}
+
+	if len(blocks) == 0 {
+		return nil, nil, fmt.Errorf("No certificate found in response for %s", certURL)
+	}
+
+	cert = pem.EncodeToMemory(blocks[0])
+	if bundle {
+		for _, block := range blocks[1:] {
+			cert = append(cert, pem.EncodeToMemory(block)...)
+		}
+	}
+
+	if len(blocks) > 1 {
+		issuer = pem.EncodeToMemory(blocks[1])
+	}
+
+	return cert, issuer, nil
+}
+
+// generateCSR builds a PKCS#10 certificate signing request for domains,
+// optionally requesting OCSP Must-Staple.
+func generateCSR(privKey crypto.PrivateKey, domains []string, mustStaple bool) ([]byte, error) {
+	csrTemplate := &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domains[0]},
+		DNSNames: domains,
+	}
+
+	if mustStaple {
+		csrTemplate.ExtraExtensions = append(csrTemplate.ExtraExtensions, pkix.Extension{
+			Id:    tlsFeatureExtensionOID,
+			Value: ocspMustStapleFeature,
+		})
+	}
+
+	return x509.CreateCertificateRequest(rand.Reader, csrTemplate, privKey)
+}
+
+// pemEncodePrivateKey PEM-encodes an RSA or ECDSA private key.
+func pemEncodePrivateKey(key crypto.PrivateKey) ([]byte, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}), nil
+	case *ecdsa.PrivateKey:
+		keyBytes, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), nil
+	default:
+		return nil, errors.New("Unsupported private key type")
+	}
+}
+
+// parsePEMPrivateKey parses a PEM-encoded RSA or ECDSA private key, as
+// produced by pemEncodePrivateKey.
+func parsePEMPrivateKey(keyBytes []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(keyBytes)
+	if block == nil {
+		return nil, errors.New("Invalid PEM private key")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("Unsupported private key type %s", block.Type)
+	}
+}
@@ -0,0 +1,92 @@
+package acme
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) (*Client, string) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	privKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Client{jws: &jws{privKey: privKey}}, server.URL
+}
+
+func TestDownloadCertificate(t *testing.T) {
+	leaf := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("leaf")})
+	issuer := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("issuer")})
+
+	c, certURL := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Replay-Nonce", "testnonce")
+		if r.Method == http.MethodPost {
+			w.Write(append(leaf, issuer...))
+		}
+	})
+
+	cert, issuerCert, err := c.downloadCertificate(certURL, false)
+	if err != nil {
+		t.Fatalf("downloadCertificate returned an unexpected error: %v", err)
+	}
+
+	if string(cert) != string(leaf) {
+		t.Errorf("got leaf certificate %q, want %q", cert, leaf)
+	}
+	if string(issuerCert) != string(issuer) {
+		t.Errorf("got issuer certificate %q, want %q", issuerCert, issuer)
+	}
+}
+
+type fakeOrderService struct {
+	orders []ExtendedOrder
+	i      int
+}
+
+func (f *fakeOrderService) New(domains []string) (ExtendedOrder, error) { return ExtendedOrder{}, nil }
+
+func (f *fakeOrderService) Get(orderURL string) (ExtendedOrder, error) {
+	order := f.orders[f.i]
+	if f.i < len(f.orders)-1 {
+		f.i++
+	}
+	return order, nil
+}
+
+func (f *fakeOrderService) UpdateForCSR(orderURL string, csr []byte) (ExtendedOrder, error) {
+	return ExtendedOrder{}, nil
+}
+
+func TestWaitOrderReturnsOnceStatusReached(t *testing.T) {
+	c := &Client{orders: &fakeOrderService{orders: []ExtendedOrder{
+		{Order: Order{Status: StatusPending}},
+		{Order: Order{Status: StatusReady}},
+	}}}
+
+	order, err := c.waitOrder("https://example.test/order/1", StatusReady)
+	if err != nil {
+		t.Fatalf("waitOrder returned an unexpected error: %v", err)
+	}
+	if order.Status != StatusReady {
+		t.Errorf("got order status %q, want %q", order.Status, StatusReady)
+	}
+}
+
+func TestWaitOrderReturnsErrorWhenInvalid(t *testing.T) {
+	c := &Client{orders: &fakeOrderService{orders: []ExtendedOrder{
+		{Order: Order{Status: StatusInvalid}},
+	}}}
+
+	if _, err := c.waitOrder("https://example.test/order/1", StatusReady); err == nil {
+		t.Fatal("waitOrder expected an error for an invalid order, got none")
+	}
+}
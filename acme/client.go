@@ -1,6 +1,7 @@
 package acme
 
 import (
+	"crypto"
 	"crypto/rsa"
 	"encoding/json"
 	"errors"
@@ -9,7 +10,6 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"regexp"
 	"strings"
 )
 
@@ -29,7 +29,7 @@ func logger() *log.Logger {
 type User interface {
 	GetEmail() string
 	GetRegistration() *RegistrationResource
-	GetPrivateKey() *rsa.PrivateKey
+	GetPrivateKey() crypto.PrivateKey
 }
 
 type solver interface {
@@ -37,18 +37,38 @@ type solver interface {
 	Solve(challenge challenge, domain string)
 }
 
+// preSolver is an optional interface a solver can implement to publish
+// whatever is needed to satisfy a challenge before any challenge is solved,
+// e.g. so DNS TXT records for many domains can be published and propagate
+// in parallel instead of one at a time.
+type preSolver interface {
+	PreSolve(challenge challenge, domain string) error
+}
+
+// cleanup is an optional interface a solver can implement to remove
+// whatever it published for a challenge once the challenges have been solved.
+type cleanup interface {
+	CleanUp(challenge challenge, domain string) error
+}
+
 // Client is the user-friendy way to ACME
 type Client struct {
-	regURL  string
-	user    User
-	jws     *jws
-	Solvers map[string]solver
+	regURL        string
+	user          User
+	jws           *jws
+	keyType       KeyType
+	Solvers       map[string]solver
+	orders        orderService
+	revokeCertURL string
 }
 
-// NewClient creates a new client for the set user.
-func NewClient(caURL string, usr User, optPort string) *Client {
-	if err := usr.GetPrivateKey().Validate(); err != nil {
-		logger().Fatalf("Could not validate the private account key of %s -> %v", usr.GetEmail(), err)
+// NewClient creates a new client for the set user. keyType is used when
+// generating the CSR and certificate private key for Obtain.
+func NewClient(caURL string, usr User, keyType KeyType, optPort string) *Client {
+	if rsaKey, ok := usr.GetPrivateKey().(*rsa.PrivateKey); ok {
+		if err := rsaKey.Validate(); err != nil {
+			logger().Fatalf("Could not validate the private account key of %s -> %v", usr.GetEmail(), err)
+		}
 	}
 
 	jws := &jws{privKey: usr.GetPrivateKey()}
@@ -58,7 +78,21 @@ func NewClient(caURL string, usr User, optPort string) *Client {
 	solvers["simpleHttp"] = &simpleHTTPChallenge{jws: jws}
 	solvers["dvsni"] = &dvsniChallenge{}
 
-	return &Client{regURL: caURL, user: usr, jws: jws}
+	return &Client{regURL: caURL, user: usr, jws: jws, keyType: keyType, Solvers: solvers}
+}
+
+// SetChallengeProvider specifies a custom provider that can solve the
+// given challenge type, e.g. a ChallengeProvider for "dns-01" that
+// publishes TXT records through a DNS hosting API.
+func (c *Client) SetChallengeProvider(challengeType string, p ChallengeProvider) error {
+	switch challengeType {
+	case "dns-01":
+		c.Solvers["dns-01"] = &dnsChallenge{jws: c.jws, provider: p}
+	default:
+		return fmt.Errorf("Unknown challenge type %s", challengeType)
+	}
+
+	return nil
 }
 
 // Register the current account to the ACME server.
@@ -80,7 +114,7 @@ func (c *Client) Register() (*RegistrationResource, error) {
 	}
 
 	var serverReg Registration
-	decoder := json.NewDecoder(resp.Body)
+	decoder := json.NewDecoder(limitBody(resp.Body))
 	err = decoder.Decode(&serverReg)
 	if err != nil {
 		return nil, err
@@ -90,12 +124,12 @@ func (c *Client) Register() (*RegistrationResource, error) {
 
 	links := parseLinks(resp.Header["Link"])
 	reg.URI = resp.Header.Get("Location")
-	if links["terms-of-service"] != "" {
-		reg.TosURL = links["terms-of-service"]
+	if tos := links["terms-of-service"]; len(tos) > 0 {
+		reg.TosURL = tos[0]
 	}
 
-	if links["next"] != "" {
-		reg.NewAuthzURL = links["next"]
+	if next := links["next"]; len(next) > 0 {
+		reg.NewAuthzURL = next[0]
 	} else {
 		return nil, errors.New("The server did not return enough information to proceed...")
 	}
@@ -142,18 +176,56 @@ func (c *Client) ObtainCertificates(domains []string) error {
 }
 
 // Looks through the challenge combinations to find a solvable match.
-// Then solves the challenges in series and returns.
+// Then solves the challenges in three phases: first every solver that
+// supports it gets to pre-solve (e.g. publish DNS TXT records for all
+// domains so they can propagate in parallel), then every challenge is
+// solved, then every solver that supports it cleans up after itself.
 func (c *Client) solveChallenges(challenges []*authorizationResource) error {
-	// loop through the resources, basically through the domains.
+	type solverChallenge struct {
+		authz   *authorizationResource
+		solvers map[int]solver
+	}
+
+	// loop through the resources, basically through the domains, and make
+	// sure we have a solver for every one of them before doing anything.
+	var toSolve []solverChallenge
 	for _, authz := range challenges {
-		// no solvers - no solving
-		if solvers := c.chooseSolvers(authz.Body); solvers != nil {
-			for i, solver := range solvers {
-				solver.Solve(authz.Body.Challenges[i], authz.Domain)
-			}
-		} else {
+		solvers := c.chooseSolvers(authz.Body)
+		if solvers == nil {
 			return fmt.Errorf("Could not determine solvers for %s", authz.Domain)
 		}
+		toSolve = append(toSolve, solverChallenge{authz: authz, solvers: solvers})
+	}
+
+	// Register cleanup before pre-solving, so that if pre-solving fails
+	// partway through, whatever was already published for earlier domains
+	// still gets cleaned up instead of being left behind.
+	defer func() {
+		for _, sc := range toSolve {
+			for i, slv := range sc.solvers {
+				if cu, ok := slv.(cleanup); ok {
+					if err := cu.CleanUp(sc.authz.Body.Challenges[i], sc.authz.Domain); err != nil {
+						logger().Printf("Error cleaning up challenge for %s: %v", sc.authz.Domain, err)
+					}
+				}
+			}
+		}
+	}()
+
+	for _, sc := range toSolve {
+		for i, slv := range sc.solvers {
+			if p, ok := slv.(preSolver); ok {
+				if err := p.PreSolve(sc.authz.Body.Challenges[i], sc.authz.Domain); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, sc := range toSolve {
+		for i, slv := range sc.solvers {
+			slv.Solve(sc.authz.Body.Challenges[i], sc.authz.Domain)
+		}
 	}
 
 	return nil
@@ -202,18 +274,19 @@ func (c *Client) getChallenges(domains []string) []*authorizationResource {
 			}
 
 			links := parseLinks(resp.Header["Link"])
-			if links["next"] == "" {
+			next := links["next"]
+			if len(next) == 0 {
 				logger().Fatalln("The server did not provide enough information to proceed.")
 			}
 
 			var authz authorization
-			decoder := json.NewDecoder(resp.Body)
+			decoder := json.NewDecoder(limitBody(resp.Body))
 			err = decoder.Decode(&authz)
 			if err != nil {
 				errc <- err
 			}
 
-			resc <- &authorizationResource{Body: authz, NewCertURL: links["next"], Domain: domain}
+			resc <- &authorizationResource{Body: authz, NewCertURL: next[0], Domain: domain}
 
 		}(domain)
 	}
@@ -242,25 +315,86 @@ func logResponseHeaders(resp *http.Response) {
 }
 
 func logResponseBody(resp *http.Response) {
-	body, _ := ioutil.ReadAll(resp.Body)
+	body, _ := ioutil.ReadAll(limitBody(resp.Body))
 	logger().Printf("Returned json data: \n%s", body)
 }
 
-func parseLinks(links []string) map[string]string {
-	aBrkt := regexp.MustCompile("[<>]")
-	slver := regexp.MustCompile("(.+) *= *\"(.+)\"")
-	linkMap := make(map[string]string)
+// parseLinks parses HTTP Link headers per RFC 5988, returning the target
+// URLs keyed by each of their rel values. A single link may declare more
+// than one space-separated rel, and a given rel may be shared by more
+// than one link, so callers get every match rather than just the last one.
+func parseLinks(links []string) map[string][]string {
+	linkMap := make(map[string][]string)
 
 	for _, link := range links {
+		for _, single := range splitTopLevel(link, ',') {
+			single = strings.TrimSpace(single)
+			if single == "" {
+				continue
+			}
+
+			parts := splitTopLevel(single, ';')
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			target = target[1 : len(target)-1]
 
-		link = aBrkt.ReplaceAllString(link, "")
-		parts := strings.Split(link, ";")
+			for _, param := range parts[1:] {
+				key, value, ok := parseLinkParam(param)
+				if !ok || strings.ToLower(key) != "rel" {
+					continue
+				}
 
-		matches := slver.FindStringSubmatch(parts[1])
-		if len(matches) > 0 {
-			linkMap[matches[2]] = parts[0]
+				for _, rel := range strings.Fields(value) {
+					linkMap[rel] = append(linkMap[rel], target)
+				}
+			}
 		}
 	}
 
 	return linkMap
 }
+
+// parseLinkParam splits a single "key=value" Link header parameter,
+// stripping any surrounding quotes from the value.
+func parseLinkParam(param string) (key, value string, ok bool) {
+	kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+	if len(kv) != 2 {
+		return "", "", false
+	}
+
+	return strings.TrimSpace(kv[0]), strings.Trim(strings.TrimSpace(kv[1]), `"`), true
+}
+
+// splitTopLevel splits s on sep, ignoring any sep found inside a <...>
+// URL reference or a "..." quoted string, so that commas and semicolons
+// appearing there aren't mistaken for field separators.
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	var inQuotes, inAngle bool
+	start := 0
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case '<':
+			if !inQuotes {
+				inAngle = true
+			}
+		case '>':
+			if !inQuotes {
+				inAngle = false
+			}
+		case sep:
+			if !inQuotes && !inAngle {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, s[start:])
+
+	return parts
+}
@@ -0,0 +1,191 @@
+package acme
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseLinks(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		links []string
+		want  map[string][]string
+	}{
+		{
+			desc:  "rel as the second parameter",
+			links: []string{`<https://example.com/acme/new-authz>; rel="next"`},
+			want:  map[string][]string{"next": {"https://example.com/acme/new-authz"}},
+		},
+		{
+			desc:  "rel in a non-second position",
+			links: []string{`<https://example.com/acme/terms>; title="terms"; rel="terms-of-service"`},
+			want:  map[string][]string{"terms-of-service": {"https://example.com/acme/terms"}},
+		},
+		{
+			desc: "multiple links in one header, comma separated",
+			links: []string{
+				`<https://example.com/acme/new-authz>; rel="next", <https://example.com/acme/terms>; rel="terms-of-service"`,
+			},
+			want: map[string][]string{
+				"next":             {"https://example.com/acme/new-authz"},
+				"terms-of-service": {"https://example.com/acme/terms"},
+			},
+		},
+		{
+			desc:  "a comma inside the URL reference is not a separator",
+			links: []string{`<https://example.com/acme/a,b>; rel="next"`},
+			want:  map[string][]string{"next": {"https://example.com/acme/a,b"}},
+		},
+		{
+			desc:  "multi-valued rel",
+			links: []string{`<https://example.com/acme/both>; rel="next alternate"`},
+			want: map[string][]string{
+				"next":      {"https://example.com/acme/both"},
+				"alternate": {"https://example.com/acme/both"},
+			},
+		},
+		{
+			desc: "the same rel on more than one link",
+			links: []string{
+				`<https://example.com/acme/up1>; rel="up"`,
+				`<https://example.com/acme/up2>; rel="up"`,
+			},
+			want: map[string][]string{
+				"up": {"https://example.com/acme/up1", "https://example.com/acme/up2"},
+			},
+		},
+		{
+			desc:  "no links",
+			links: nil,
+			want:  map[string][]string{},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			got := parseLinks(test.links)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("parseLinks(%v) = %v, want %v", test.links, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSplitTopLevel(t *testing.T) {
+	testCases := []struct {
+		desc string
+		in   string
+		sep  byte
+		want []string
+	}{
+		{
+			desc: "no separator present",
+			in:   `<https://example.com>; rel="next"`,
+			sep:  ',',
+			want: []string{`<https://example.com>; rel="next"`},
+		},
+		{
+			desc: "separator inside angle brackets is not split on",
+			in:   `<https://example.com/a,b>; rel="next"`,
+			sep:  ',',
+			want: []string{`<https://example.com/a,b>; rel="next"`},
+		},
+		{
+			desc: "separator inside a quoted string is not split on",
+			in:   `<https://example.com>; rel="next, alternate"`,
+			sep:  ',',
+			want: []string{`<https://example.com>; rel="next, alternate"`},
+		},
+		{
+			desc: "splits on every top-level separator",
+			in:   `<a>; rel="x", <b>; rel="y"`,
+			sep:  ',',
+			want: []string{`<a>; rel="x"`, ` <b>; rel="y"`},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			got := splitTopLevel(test.in, test.sep)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("splitTopLevel(%q, %q) = %v, want %v", test.in, test.sep, got, test.want)
+			}
+		})
+	}
+}
+
+// fakeSolver is a solver that also implements preSolver and cleanup, so
+// tests can observe the order solveChallenges calls them in. PreSolve fails
+// for any domain in failDomains.
+type fakeSolver struct {
+	calls       *[]string
+	failDomains map[string]bool
+}
+
+func (s *fakeSolver) CanSolve() bool { return true }
+
+func (s *fakeSolver) PreSolve(chlng challenge, domain string) error {
+	*s.calls = append(*s.calls, "presolve:"+domain)
+	if s.failDomains[domain] {
+		return errors.New("boom")
+	}
+	return nil
+}
+
+func (s *fakeSolver) Solve(chlng challenge, domain string) {
+	*s.calls = append(*s.calls, "solve:"+domain)
+}
+
+func (s *fakeSolver) CleanUp(chlng challenge, domain string) error {
+	*s.calls = append(*s.calls, "cleanup:"+domain)
+	return nil
+}
+
+func newFakeAuthz(domain string) *authorizationResource {
+	return &authorizationResource{
+		Domain: domain,
+		Body: authorization{
+			Identifier:   identifier{Value: domain},
+			Challenges:   []challenge{{Type: "fake-01"}},
+			Combinations: [][]int{{0}},
+		},
+	}
+}
+
+func TestSolveChallengesCleansUpAlreadyPresolvedDomains(t *testing.T) {
+	var calls []string
+
+	fake := &fakeSolver{calls: &calls, failDomains: map[string]bool{"fail.example.com": true}}
+	c := &Client{Solvers: map[string]solver{"fake-01": fake}}
+
+	authzOK := newFakeAuthz("ok.example.com")
+	authzFail := newFakeAuthz("fail.example.com")
+
+	err := c.solveChallenges([]*authorizationResource{authzOK, authzFail})
+	if err == nil {
+		t.Fatal("solveChallenges expected an error from the failing PreSolve, got none")
+	}
+
+	want := []string{"presolve:ok.example.com", "presolve:fail.example.com"}
+	if len(calls) < len(want) {
+		t.Fatalf("got calls %v, want at least %v", calls, want)
+	}
+	for i, w := range want {
+		if calls[i] != w {
+			t.Errorf("call %d = %q, want %q", i, calls[i], w)
+		}
+	}
+
+	// ok.example.com's PreSolve succeeded before fail.example.com's failed;
+	// it must still be cleaned up instead of left behind.
+	var cleanedUp bool
+	for _, call := range calls {
+		if call == "cleanup:ok.example.com" {
+			cleanedUp = true
+		}
+	}
+	if !cleanedUp {
+		t.Errorf("got calls %v, want a cleanup:ok.example.com call", calls)
+	}
+}
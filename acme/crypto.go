@@ -0,0 +1,41 @@
+package acme
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+)
+
+// KeyType represents the key algo as well as the key size or curve to use.
+type KeyType string
+
+// Constants for all key types we support.
+const (
+	RSA2048 = KeyType("RSA2048")
+	RSA4096 = KeyType("RSA4096")
+	RSA8192 = KeyType("RSA8192")
+	EC256   = KeyType("EC256")
+	EC384   = KeyType("EC384")
+)
+
+// GeneratePrivateKey generates a private key of the given KeyType, to be
+// used for an account key or a certificate key.
+func GeneratePrivateKey(keyType KeyType) (crypto.PrivateKey, error) {
+	switch keyType {
+	case EC256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case EC384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case RSA2048:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case RSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case RSA8192:
+		return rsa.GenerateKey(rand.Reader, 8192)
+	}
+
+	return nil, fmt.Errorf("Invalid KeyType: %s", keyType)
+}
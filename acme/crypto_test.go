@@ -0,0 +1,63 @@
+package acme
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"testing"
+)
+
+func TestGeneratePrivateKey(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		keyType KeyType
+		check   func(t *testing.T, key interface{})
+	}{
+		{
+			desc:    "RSA2048",
+			keyType: RSA2048,
+			check: func(t *testing.T, key interface{}) {
+				rsaKey, ok := key.(*rsa.PrivateKey)
+				if !ok {
+					t.Fatalf("got %T, want *rsa.PrivateKey", key)
+				}
+				if bits := rsaKey.N.BitLen(); bits != 2048 {
+					t.Errorf("got a %d-bit key, want 2048", bits)
+				}
+			},
+		},
+		{
+			desc:    "EC256",
+			keyType: EC256,
+			check: func(t *testing.T, key interface{}) {
+				if _, ok := key.(*ecdsa.PrivateKey); !ok {
+					t.Fatalf("got %T, want *ecdsa.PrivateKey", key)
+				}
+			},
+		},
+		{
+			desc:    "EC384",
+			keyType: EC384,
+			check: func(t *testing.T, key interface{}) {
+				if _, ok := key.(*ecdsa.PrivateKey); !ok {
+					t.Fatalf("got %T, want *ecdsa.PrivateKey", key)
+				}
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			key, err := GeneratePrivateKey(test.keyType)
+			if err != nil {
+				t.Fatalf("GeneratePrivateKey(%s) returned an unexpected error: %v", test.keyType, err)
+			}
+			test.check(t, key)
+		})
+	}
+}
+
+func TestGeneratePrivateKeyInvalidType(t *testing.T) {
+	if _, err := GeneratePrivateKey(KeyType("bogus")); err == nil {
+		t.Fatal("GeneratePrivateKey with an invalid KeyType expected an error, got none")
+	}
+}
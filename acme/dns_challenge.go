@@ -0,0 +1,257 @@
+package acme
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	dnsPropagationCheckInterval = 2 * time.Second
+	dnsPropagationTimeout       = 60 * time.Second
+)
+
+// ChallengeProvider enables implementing a custom dns-01 challenge
+// provider, e.g. one that talks to a DNS hosting API to publish and
+// remove the TXT record a domain's authorization requires.
+type ChallengeProvider interface {
+	Present(domain, token, keyAuth string) error
+	CleanUp(domain, token, keyAuth string) error
+}
+
+// dnsChallenge implements the dns-01 challenge. It delegates the actual
+// publishing of the TXT record to a ChallengeProvider, and waits for the
+// record to be visible on the domain's authoritative nameservers before
+// letting the CA validate.
+type dnsChallenge struct {
+	jws      *jws
+	provider ChallengeProvider
+}
+
+func (s *dnsChallenge) CanSolve() bool {
+	return s.provider != nil
+}
+
+// PreSolve presents the TXT record for domain so that it can start
+// propagating while the other domains in this order are pre-solved too.
+func (s *dnsChallenge) PreSolve(chlng challenge, domain string) error {
+	keyAuth, err := getKeyAuthorization(chlng.Token, s.jws.privKey)
+	if err != nil {
+		return fmt.Errorf("Could not generate key authorization for %s: %v", domain, err)
+	}
+
+	if err := s.provider.Present(domain, chlng.Token, keyAuth); err != nil {
+		return fmt.Errorf("Error presenting token for %s: %v", domain, err)
+	}
+
+	return nil
+}
+
+// Solve waits for the TXT record published in PreSolve to show up on every
+// nameserver authoritative for domain, then tells the CA the challenge is
+// ready to be validated.
+func (s *dnsChallenge) Solve(chlng challenge, domain string) {
+	logger().Printf("Trying to solve DNS-01 for %s", domain)
+
+	keyAuth, err := getKeyAuthorization(chlng.Token, s.jws.privKey)
+	if err != nil {
+		logger().Printf("Could not generate key authorization for %s: %v", domain, err)
+		return
+	}
+
+	fqdn, value := dns01Record(domain, keyAuth)
+	if err := preCheckDNS(fqdn, value); err != nil {
+		logger().Print(err)
+		return
+	}
+
+	if err := notifyChallenge(s.jws, chlng, keyAuth); err != nil {
+		logger().Printf("Could not notify the CA that %s is ready to be validated: %v", domain, err)
+	}
+}
+
+// notifyChallenge tells the ACME server that keyAuth is ready to be
+// validated for chlng, by posting it to the challenge's validation URI.
+func notifyChallenge(j *jws, chlng challenge, keyAuth string) error {
+	jsonBytes, err := json.Marshal(struct {
+		KeyAuthorization string `json:"keyAuthorization"`
+	}{KeyAuthorization: keyAuth})
+	if err != nil {
+		return err
+	}
+
+	resp, err := j.post(chlng.URI, jsonBytes)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("Server returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// CleanUp removes the TXT record published in PreSolve.
+func (s *dnsChallenge) CleanUp(chlng challenge, domain string) error {
+	keyAuth, err := getKeyAuthorization(chlng.Token, s.jws.privKey)
+	if err != nil {
+		return err
+	}
+
+	return s.provider.CleanUp(domain, chlng.Token, keyAuth)
+}
+
+// dns01Record returns the FQDN and expected TXT record value for the
+// dns-01 challenge of domain with the given key authorization.
+func dns01Record(domain, keyAuth string) (fqdn string, value string) {
+	sum := sha256.Sum256([]byte(keyAuth))
+	return fmt.Sprintf("_acme-challenge.%s.", domain), base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// getKeyAuthorization computes the key authorization for a challenge
+// token, as defined by the ACME spec: token + "." + base64url(SHA-256 JWK
+// thumbprint of the account key).
+func getKeyAuthorization(token string, privKey crypto.PrivateKey) (string, error) {
+	signer, ok := privKey.(crypto.Signer)
+	if !ok {
+		return "", errors.New("Unsupported private key type")
+	}
+
+	jwk := keyAsJWK(signer.Public())
+	if jwk == nil {
+		return "", errors.New("Unsupported private key type")
+	}
+
+	thumbBytes, err := jwk.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+
+	// unpad the base64URL
+	keyThumb := base64.URLEncoding.EncodeToString(thumbBytes)
+	if index := strings.Index(keyThumb, "="); index != -1 {
+		keyThumb = keyThumb[:index]
+	}
+
+	return fmt.Sprintf("%s.%s", token, keyThumb), nil
+}
+
+// preCheckDNS polls checkDNSPropagation, with exponential backoff, until
+// it succeeds or dnsPropagationTimeout elapses. A freshly published TXT
+// record typically isn't visible on every authoritative nameserver yet by
+// the time this is called, so a single pass would fail on pretty much
+// every real-world run.
+func preCheckDNS(fqdn, value string) error {
+	deadline := time.Now().Add(dnsPropagationTimeout)
+
+	var err error
+	for wait := dnsPropagationCheckInterval; ; wait *= 2 {
+		if err = checkDNSPropagation(fqdn, value); err == nil {
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("Timed out waiting for DNS propagation of %s: %v", fqdn, err)
+		}
+
+		if wait > remaining {
+			wait = remaining
+		}
+		time.Sleep(wait)
+	}
+}
+
+// checkDNSPropagation walks up fqdn to find the nameservers authoritative
+// for its zone, then queries each of them directly for a TXT record
+// matching value. It only returns nil once every authoritative nameserver
+// agrees, instead of relying on a possibly-stale recursive resolver cache.
+func checkDNSPropagation(fqdn, value string) error {
+	nameservers, err := lookupNameservers(fqdn)
+	if err != nil {
+		return err
+	}
+
+	for _, ns := range nameservers {
+		r, err := dnsQuery(fqdn, dns.TypeTXT, ns, true)
+		if err != nil {
+			return err
+		}
+
+		if r.Rcode != dns.RcodeSuccess {
+			return fmt.Errorf("NS %s returned %s for %s", ns, dns.RcodeToString[r.Rcode], fqdn)
+		}
+
+		var found bool
+		for _, rr := range r.Answer {
+			if txt, ok := rr.(*dns.TXT); ok {
+				for _, s := range txt.Txt {
+					if s == value {
+						found = true
+					}
+				}
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("NS %s did not return the expected TXT record for %s", ns, fqdn)
+		}
+	}
+
+	return nil
+}
+
+// lookupNameservers returns the nameservers authoritative for fqdn's zone,
+// found by walking up the domain with net.LookupNS until an NS set shows
+// up.
+func lookupNameservers(fqdn string) ([]string, error) {
+	domain := strings.TrimSuffix(fqdn, ".")
+
+	var authoritative []*net.NS
+	for {
+		nss, err := net.LookupNS(domain)
+		if err == nil && len(nss) > 0 {
+			authoritative = nss
+			break
+		}
+
+		index := strings.Index(domain, ".")
+		if index == -1 {
+			break
+		}
+		domain = domain[index+1:]
+	}
+
+	if len(authoritative) == 0 {
+		return nil, fmt.Errorf("Could not determine the authoritative nameservers for %s", fqdn)
+	}
+
+	var hosts []string
+	for _, ns := range authoritative {
+		hosts = append(hosts, net.JoinHostPort(strings.TrimSuffix(ns.Host, "."), "53"))
+	}
+
+	return hosts, nil
+}
+
+func dnsQuery(fqdn string, rtype uint16, nameserver string, recursive bool) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(fqdn, rtype)
+	m.RecursionDesired = recursive
+
+	c := new(dns.Client)
+	c.Timeout = 10 * time.Second
+
+	r, _, err := c.Exchange(m, nameserver)
+	return r, err
+}
@@ -0,0 +1,37 @@
+package acme
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+)
+
+// DNSProviderManual is a ChallengeProvider which simply prints the TXT
+// record the user needs to create, and waits on stdin for confirmation
+// that it has been published before continuing.
+type DNSProviderManual struct{}
+
+// NewDNSProviderManual returns a DNSProviderManual instance.
+func NewDNSProviderManual() (*DNSProviderManual, error) {
+	return &DNSProviderManual{}, nil
+}
+
+// Present prints instructions for the user to manually create the TXT
+// record and waits for them to press enter once it is done.
+func (*DNSProviderManual) Present(domain, token, keyAuth string) error {
+	fqdn, value := dns01Record(domain, keyAuth)
+
+	fmt.Printf("Please create the following TXT record in your DNS zone:\n%s TXT %s\n", fqdn, value)
+	fmt.Println("Press 'Enter' when you are done")
+
+	_, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	return err
+}
+
+// CleanUp prints instructions for the user to manually remove the TXT
+// record that was created in Present.
+func (*DNSProviderManual) CleanUp(domain, token, keyAuth string) error {
+	fqdn, _ := dns01Record(domain, keyAuth)
+	fmt.Printf("You can now remove the TXT record for %s\n", fqdn)
+	return nil
+}
@@ -39,14 +39,20 @@ func NewDNSProviderRoute53(awsAccessKey, awsSecretKey, awsRegionName string) (*D
 	return &DNSProviderRoute53{client: client}, nil
 }
 
-// CreateTXTRecord creates a TXT record using the specified parameters
-func (r *DNSProviderRoute53) CreateTXTRecord(fqdn, value string, ttl int) error {
-	return r.changeRecord("UPSERT", fqdn, value, ttl)
+// route53TTL is the TTL, in seconds, used for the TXT records this
+// provider creates.
+const route53TTL = 120
+
+// Present creates a TXT record to fulfil the dns-01 challenge.
+func (r *DNSProviderRoute53) Present(domain, token, keyAuth string) error {
+	fqdn, value := dns01Record(domain, keyAuth)
+	return r.changeRecord("UPSERT", fqdn, value, route53TTL)
 }
 
-// RemoveTXTRecord removes the TXT record matching the specified parameters
-func (r *DNSProviderRoute53) RemoveTXTRecord(fqdn, value string, ttl int) error {
-	return r.changeRecord("DELETE", fqdn, value, ttl)
+// CleanUp removes the TXT record matching the specified parameters.
+func (r *DNSProviderRoute53) CleanUp(domain, token, keyAuth string) error {
+	fqdn, value := dns01Record(domain, keyAuth)
+	return r.changeRecord("DELETE", fqdn, value, route53TTL)
 }
 
 func (r *DNSProviderRoute53) changeRecord(action, fqdn, value string, ttl int) error {
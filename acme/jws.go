@@ -2,7 +2,9 @@ package acme
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"errors"
 	"fmt"
@@ -12,7 +14,7 @@ import (
 )
 
 type jws struct {
-	privKey *rsa.PrivateKey
+	privKey crypto.PrivateKey
 	nonces  []string
 }
 
@@ -42,6 +44,7 @@ func (j *jws) post(url string, content []byte) (*http.Response, error) {
 		return nil, err
 	}
 
+	throttle()
 	resp, err := http.Post(url, "application/jose+json", bytes.NewBuffer([]byte(signedContent.FullSerialize())))
 	if err != nil {
 		return nil, err
@@ -53,8 +56,12 @@ func (j *jws) post(url string, content []byte) (*http.Response, error) {
 }
 
 func (j *jws) signContent(content []byte) (*jose.JsonWebSignature, error) {
-	// TODO: support other algorithms - RS512
-	signer, err := jose.NewSigner(jose.RS256, j.privKey)
+	alg, err := jwsAlgorithm(j.privKey)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := jose.NewSigner(alg, j.privKey)
 	if err != nil {
 		return nil, err
 	}
@@ -67,6 +74,26 @@ func (j *jws) signContent(content []byte) (*jose.JsonWebSignature, error) {
 	return signed, nil
 }
 
+// jwsAlgorithm picks the JWS signature algorithm matching the type of key,
+// so that RSA and ECDSA account keys can both be used to sign requests.
+func jwsAlgorithm(privKey crypto.PrivateKey) (jose.SignatureAlgorithm, error) {
+	switch k := privKey.(type) {
+	case *rsa.PrivateKey:
+		return jose.RS256, nil
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jose.ES256, nil
+		case elliptic.P384():
+			return jose.ES384, nil
+		default:
+			return "", fmt.Errorf("Unsupported elliptic curve %s", k.Curve.Params().Name)
+		}
+	default:
+		return "", errors.New("Unsupported private key type")
+	}
+}
+
 func (j *jws) getNonceFromResponse(resp *http.Response) error {
 	nonce := resp.Header.Get("Replay-Nonce")
 	if nonce == "" {
@@ -78,6 +105,7 @@ func (j *jws) getNonceFromResponse(resp *http.Response) error {
 }
 
 func (j *jws) getNonce(url string) error {
+	throttle()
 	resp, err := http.Head(url)
 	if err != nil {
 		return err
@@ -0,0 +1,67 @@
+package acme
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/square/go-jose"
+)
+
+func TestJWSAlgorithm(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ec256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ec384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ec521Key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		desc    string
+		privKey crypto.PrivateKey
+		want    jose.SignatureAlgorithm
+		wantErr bool
+	}{
+		{desc: "RSA key signs with RS256", privKey: rsaKey, want: jose.RS256},
+		{desc: "P256 key signs with ES256", privKey: ec256Key, want: jose.ES256},
+		{desc: "P384 key signs with ES384", privKey: ec384Key, want: jose.ES384},
+		{desc: "unsupported curve errors", privKey: ec521Key, wantErr: true},
+		{desc: "unsupported key type errors", privKey: "not a key", wantErr: true},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			alg, err := jwsAlgorithm(test.privKey)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("jwsAlgorithm(%T) expected an error, got none", test.privKey)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("jwsAlgorithm(%T) returned an unexpected error: %v", test.privKey, err)
+			}
+
+			if alg != test.want {
+				t.Errorf("jwsAlgorithm(%T) = %v, want %v", test.privKey, alg, test.want)
+			}
+		})
+	}
+}
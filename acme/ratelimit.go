@@ -0,0 +1,67 @@
+package acme
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// RequestsPerSecond caps the rate of requests this package issues against an
+// ACME server. It defaults to a little under the 20 req/s Let's Encrypt
+// documents for its API, leaving some headroom. CAs with a different rate
+// limit policy can adjust it before making any requests.
+var RequestsPerSecond = 18
+
+// maxBodySize is the largest response body we will ever read from an ACME
+// server, guarding against hostile or oversized responses.
+const maxBodySize = 1024 * 1024
+
+var (
+	tokens     chan struct{}
+	tokensOnce sync.Once
+)
+
+// throttle blocks until a token is available, enforcing RequestsPerSecond
+// across every request this package makes to the CA.
+func throttle() {
+	tokensOnce.Do(func() {
+		rps := RequestsPerSecond
+		if rps <= 0 {
+			// Guard against a misconfigured (zero or negative) rate, which
+			// would otherwise divide by zero below.
+			rps = 1
+		}
+
+		tokens = make(chan struct{}, rps)
+		for i := 0; i < rps; i++ {
+			tokens <- struct{}{}
+		}
+
+		go func() {
+			ticker := time.NewTicker(time.Second / time.Duration(rps))
+			defer ticker.Stop()
+			for range ticker.C {
+				select {
+				case tokens <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	})
+
+	<-tokens
+}
+
+// limitBody wraps r so that no more than maxBodySize bytes will ever be read
+// from an ACME server response.
+func limitBody(r io.Reader) io.Reader {
+	return io.LimitReader(r, maxBodySize)
+}
+
+// Throttle blocks until a token is available, enforcing RequestsPerSecond.
+// It's exported so that other packages issuing requests directly to the CA,
+// such as acme/api, share this package's rate limit instead of each keeping
+// their own.
+func Throttle() {
+	throttle()
+}
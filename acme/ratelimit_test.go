@@ -0,0 +1,20 @@
+package acme
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestLimitBody(t *testing.T) {
+	body := strings.Repeat("a", maxBodySize+10)
+
+	got, err := ioutil.ReadAll(limitBody(strings.NewReader(body)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(got) != maxBodySize {
+		t.Errorf("limitBody let through %d bytes, want %d", len(got), maxBodySize)
+	}
+}